@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chapsuk/ireporter"
+)
+
+// Flag values shared by every subcommand, populated by rootCmd's persistent
+// flags in main.go.
+var (
+	flagUserID     string
+	flagPassword   string
+	flagMode       string
+	flagProperties string
+	flagOutput     string
+)
+
+// loadConfig resolves credentials in increasing order of precedence:
+// Reporter.properties file, environment variables, then explicit flags.
+func loadConfig() (ireporter.Config, error) {
+	cfg := ireporter.Config{}
+
+	if props, err := readProperties(flagProperties); err == nil {
+		cfg.UserID = props["userid"]
+		cfg.Password = props["password"]
+		cfg.Mode = props["mode"]
+	} else if !os.IsNotExist(err) {
+		return cfg, err
+	}
+
+	if v := os.Getenv("REPORTER_USERID"); v != "" {
+		cfg.UserID = v
+	}
+	if v := os.Getenv("REPORTER_PASSWORD"); v != "" {
+		cfg.Password = v
+	}
+	if v := os.Getenv("REPORTER_MODE"); v != "" {
+		cfg.Mode = v
+	}
+
+	if flagUserID != "" {
+		cfg.UserID = flagUserID
+	}
+	if flagPassword != "" {
+		cfg.Password = flagPassword
+	}
+	if flagMode != "" {
+		cfg.Mode = flagMode
+	}
+
+	return cfg, nil
+}
+
+// readProperties parses a Reporter.properties-style file of key=value lines,
+// ignoring blank lines and lines starting with '#'.
+func readProperties(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	props := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		props[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return props, scanner.Err()
+}
+
+// openOutput returns a writer for flagOutput: stdout for "-", or a truncated
+// file otherwise. The caller must close the returned io.WriteCloser.
+func openOutput() (io.WriteCloser, error) {
+	if flagOutput == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(flagOutput)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
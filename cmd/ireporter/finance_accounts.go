@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/chapsuk/ireporter"
+	"github.com/spf13/cobra"
+)
+
+var financeAccountsCmd = &cobra.Command{
+	Use:   "finance-accounts",
+	Short: "Call Finance.getAccounts and list the accounts available to this user",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		client, err := ireporter.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		body, err := client.GetFinanceAccountsContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return writeRaw(body)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(financeAccountsCmd)
+}
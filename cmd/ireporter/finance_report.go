@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/chapsuk/ireporter"
+	"github.com/chapsuk/ireporter/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	financeReportAccount      int
+	financeReportVendor       int
+	financeReportRegionCode   string
+	financeReportType         string
+	financeReportFiscalYear   string
+	financeReportFiscalPeriod string
+	financeReportFormat       string
+)
+
+var financeReportCmd = &cobra.Command{
+	Use:   "finance-report",
+	Short: "Call Finance.getReport and write the report in --format json|tsv|raw",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		client, err := ireporter.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		body, err := client.GetFinanceReportContext(cmd.Context(), financeReportAccount, financeReportVendor,
+			financeReportRegionCode, financeReportType, financeReportFiscalYear, financeReportFiscalPeriod)
+		if err != nil {
+			return err
+		}
+		return writeReport(body, financeReportFormat, func(r []byte) (interface{}, error) {
+			return report.DecodeFinanceReport(bytes.NewReader(r))
+		})
+	},
+}
+
+func init() {
+	flags := financeReportCmd.Flags()
+	flags.IntVar(&financeReportAccount, "account", 0, "account number (required)")
+	flags.IntVar(&financeReportVendor, "vendor", 0, "vendor number (required)")
+	flags.StringVar(&financeReportRegionCode, "region-code", "", "region code, e.g. WW (required)")
+	flags.StringVar(&financeReportType, "report-type", "Financial", "Financial or FinancialDetail")
+	flags.StringVar(&financeReportFiscalYear, "fiscal-year", "", "fiscal year, YYYY (required)")
+	flags.StringVar(&financeReportFiscalPeriod, "fiscal-period", "", "fiscal period, two digits (required)")
+	flags.StringVar(&financeReportFormat, "format", "raw", "output format: json, tsv or raw")
+	financeReportCmd.MarkFlagRequired("account")
+	financeReportCmd.MarkFlagRequired("vendor")
+	financeReportCmd.MarkFlagRequired("region-code")
+	financeReportCmd.MarkFlagRequired("fiscal-year")
+	financeReportCmd.MarkFlagRequired("fiscal-period")
+	rootCmd.AddCommand(financeReportCmd)
+}
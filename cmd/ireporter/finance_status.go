@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/chapsuk/ireporter"
+	"github.com/spf13/cobra"
+)
+
+var financeStatusCmd = &cobra.Command{
+	Use:   "finance-status",
+	Short: "Call Finance.getStatus and print whether the finance endpoint is up",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		client, err := ireporter.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		body, err := client.GetFinanceStatusContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return writeRaw(body)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(financeStatusCmd)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/chapsuk/ireporter"
+	"github.com/spf13/cobra"
+)
+
+var financeVendorsAccount int
+
+var financeVendorsCmd = &cobra.Command{
+	Use:   "finance-vendors",
+	Short: "Call Finance.getVendorsAndRegions for the given --account",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		client, err := ireporter.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		body, err := client.GetFinanceVendorsAndRegionsContext(cmd.Context(), financeVendorsAccount)
+		if err != nil {
+			return err
+		}
+		return writeRaw(body)
+	},
+}
+
+func init() {
+	financeVendorsCmd.Flags().IntVar(&financeVendorsAccount, "account", 0, "account number (required)")
+	financeVendorsCmd.MarkFlagRequired("account")
+	rootCmd.AddCommand(financeVendorsCmd)
+}
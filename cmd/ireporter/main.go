@@ -0,0 +1,32 @@
+// Command ireporter is a CLI wrapper around the ireporter package, mirroring
+// the subcommands of Apple's Reporter.jar tool.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// rootCmd is the entry point all subcommands attach to.
+var rootCmd = &cobra.Command{
+	Use:   "ireporter",
+	Short: "Query the iTunes Connect / App Store Connect Reporter service",
+}
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&flagUserID, "userid", "", "Reporter account user ID (env REPORTER_USERID)")
+	flags.StringVar(&flagPassword, "password", "", "Reporter account password (env REPORTER_PASSWORD)")
+	flags.StringVar(&flagMode, "mode", "", "Normal or Robot.xml (env REPORTER_MODE)")
+	flags.StringVar(&flagProperties, "properties", "Reporter.properties", "path to a Reporter.properties-style credentials file")
+	flags.StringVar(&flagOutput, "output", "-", "output destination: - for stdout, or a file path")
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
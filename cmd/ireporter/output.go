@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+// writeRaw writes body as-is to the configured output destination.
+func writeRaw(body []byte) error {
+	out, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = out.Write(body)
+	return err
+}
+
+// writeJSON marshals v as indented JSON to the configured output
+// destination.
+func writeJSON(v interface{}) error {
+	out, err := openOutput()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/chapsuk/ireporter"
+	"github.com/spf13/cobra"
+)
+
+var salesAccountsCmd = &cobra.Command{
+	Use:   "sales-accounts",
+	Short: "Call Sales.getAccounts and list the accounts available to this user",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		client, err := ireporter.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		body, err := client.GetSalesAccountsContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return writeRaw(body)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(salesAccountsCmd)
+}
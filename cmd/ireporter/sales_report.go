@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/chapsuk/ireporter"
+	"github.com/chapsuk/ireporter/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	salesReportAccount  int
+	salesReportVendor   int
+	salesReportType     string
+	salesReportSubType  string
+	salesReportDateType string
+	salesReportDate     string
+	salesReportFormat   string
+)
+
+var salesReportCmd = &cobra.Command{
+	Use:   "sales-report",
+	Short: "Call Sales.getReport and write the report in --format json|tsv|raw",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		client, err := ireporter.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		body, err := client.GetSalesReportContext(cmd.Context(), salesReportAccount, salesReportVendor,
+			salesReportType, salesReportSubType, salesReportDateType, salesReportDate)
+		if err != nil {
+			return err
+		}
+		return writeReport(body, salesReportFormat, func(r []byte) (interface{}, error) {
+			return report.DecodeSalesReport(bytes.NewReader(r))
+		})
+	},
+}
+
+func init() {
+	flags := salesReportCmd.Flags()
+	flags.IntVar(&salesReportAccount, "account", 0, "account number (required)")
+	flags.IntVar(&salesReportVendor, "vendor", 0, "vendor number (required)")
+	flags.StringVar(&salesReportType, "report-type", "Sales", "report type, e.g. Sales")
+	flags.StringVar(&salesReportSubType, "report-subtype", "Summary", "Summary, Detailed or Opt-In")
+	flags.StringVar(&salesReportDateType, "date-type", "Daily", "Daily, Weekly, Monthly or Yearly")
+	flags.StringVar(&salesReportDate, "date", "", "date matching --date-type, e.g. YYYYMMDD (required)")
+	flags.StringVar(&salesReportFormat, "format", "raw", "output format: json, tsv or raw")
+	salesReportCmd.MarkFlagRequired("account")
+	salesReportCmd.MarkFlagRequired("vendor")
+	salesReportCmd.MarkFlagRequired("date")
+	rootCmd.AddCommand(salesReportCmd)
+}
+
+// writeReport writes body to the configured output destination according to
+// format: "raw" writes the untouched API response, "tsv" writes the ungzipped
+// TSV text, and "json" decodes rows with decode and writes them as JSON.
+func writeReport(body []byte, format string, decode func([]byte) (interface{}, error)) error {
+	switch format {
+	case "raw":
+		return writeRaw(body)
+	case "tsv":
+		tsv, err := gunzip(body)
+		if err != nil {
+			return err
+		}
+		return writeRaw(tsv)
+	case "json":
+		rows, err := decode(body)
+		if err != nil {
+			return err
+		}
+		return writeJSON(rows)
+	default:
+		return fmt.Errorf("unknown --format %q, use: json, tsv or raw", format)
+	}
+}
+
+// gunzip decompresses a gzip-compressed report body for --format tsv. It
+// returns the body untouched if it is not gzip-compressed, e.g. an XML error.
+func gunzip(body []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return body, nil
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/chapsuk/ireporter"
+	"github.com/spf13/cobra"
+)
+
+var salesVendorsAccount int
+
+var salesVendorsCmd = &cobra.Command{
+	Use:   "sales-vendors",
+	Short: "Call Sales.getVendors for the given --account",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		client, err := ireporter.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		body, err := client.GetSalesVendorsContext(cmd.Context(), salesVendorsAccount)
+		if err != nil {
+			return err
+		}
+		return writeRaw(body)
+	},
+}
+
+func init() {
+	salesVendorsCmd.Flags().IntVar(&salesVendorsAccount, "account", 0, "account number (required)")
+	salesVendorsCmd.MarkFlagRequired("account")
+	rootCmd.AddCommand(salesVendorsCmd)
+}
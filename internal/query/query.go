@@ -0,0 +1,131 @@
+// Package query builds the queryInput strings the Reporter service expects,
+// replacing the hand-escaped literals that used to be inlined in each Client
+// method.
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Target identifies which Reporter service endpoint a Request targets.
+type Target int
+
+const (
+	// Sales targets the Sales.* actions on the sales endpoint.
+	Sales Target = iota
+	// Finance targets the Finance.* actions on the finance endpoint.
+	Finance
+)
+
+// QueryInput fluently builds the canonical "[p=Reporter.properties, ...]"
+// form accepted by Sales.* and Finance.* actions.
+type QueryInput struct {
+	account *int
+	action  string
+	args    []string
+}
+
+// NewQueryInput returns an empty, ready-to-configure QueryInput.
+func NewQueryInput() *QueryInput {
+	return &QueryInput{}
+}
+
+// WithAccount sets the "a=" account segment.
+func (q *QueryInput) WithAccount(account int) *QueryInput {
+	q.account = &account
+	return q
+}
+
+// WithVendor appends vendor as a positional argument. Arguments keep the
+// order of the calls, so vendor comes first, as Sales.getReport and
+// Finance.getReport expect after the action, only when WithVendor is called
+// before WithArgs.
+func (q *QueryInput) WithVendor(vendor int) *QueryInput {
+	q.args = append(q.args, strconv.Itoa(vendor))
+	return q
+}
+
+// WithAction sets the Sales.* or Finance.* action name.
+func (q *QueryInput) WithAction(action string) *QueryInput {
+	q.action = action
+	return q
+}
+
+// WithArgs appends positional arguments, in order, after the action and any
+// arguments appended before it.
+func (q *QueryInput) WithArgs(args ...string) *QueryInput {
+	q.args = append(q.args, args...)
+	return q
+}
+
+// String renders the canonical, unescaped "[p=Reporter.properties, ...]"
+// form, e.g. "[p=Reporter.properties, a=1, Sales.getReport, 2,Summary,Daily,20200101]".
+func (q *QueryInput) String() string {
+	segments := []string{"p=Reporter.properties"}
+	if q.account != nil {
+		segments = append(segments, fmt.Sprintf("a=%d", *q.account))
+	}
+	if q.action != "" {
+		segments = append(segments, q.action)
+	}
+	out := "[" + strings.Join(segments, ", ")
+	if len(q.args) > 0 {
+		out += ", " + strings.Join(q.args, ",")
+	}
+	return out + "]"
+}
+
+// Encode renders the percent-escaped wire form Reporter expects in the
+// queryInput JSON field.
+func (q *QueryInput) Encode() string {
+	return url.QueryEscape(q.String())
+}
+
+// Request pairs a QueryInput with the Target service it should be sent to,
+// so a fluent chain can be passed around as a single value.
+type Request struct {
+	Target Target
+	Query  *QueryInput
+}
+
+// NewRequest returns a Request targeting the given service, with an empty
+// QueryInput ready for fluent configuration.
+func NewRequest(target Target) *Request {
+	return &Request{Target: target, Query: NewQueryInput()}
+}
+
+// WithAccount sets the "a=" account segment and returns the Request for
+// further chaining.
+func (r *Request) WithAccount(account int) *Request {
+	r.Query.WithAccount(account)
+	return r
+}
+
+// WithVendor appends vendor as a positional argument and returns the Request
+// for further chaining.
+func (r *Request) WithVendor(vendor int) *Request {
+	r.Query.WithVendor(vendor)
+	return r
+}
+
+// WithAction sets the Sales.* or Finance.* action name and returns the
+// Request for further chaining.
+func (r *Request) WithAction(action string) *Request {
+	r.Query.WithAction(action)
+	return r
+}
+
+// WithArgs appends positional arguments and returns the Request for further
+// chaining.
+func (r *Request) WithArgs(args ...string) *Request {
+	r.Query.WithArgs(args...)
+	return r
+}
+
+// Encode renders the percent-escaped queryInput wire form.
+func (r *Request) Encode() string {
+	return r.Query.Encode()
+}
@@ -0,0 +1,72 @@
+package query
+
+import "testing"
+
+func TestQueryInputString(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *QueryInput
+		want string
+	}{
+		{
+			name: "action only",
+			q:    NewQueryInput().WithAction("Sales.getStatus"),
+			want: "[p=Reporter.properties, Sales.getStatus]",
+		},
+		{
+			name: "account and action",
+			q:    NewQueryInput().WithAccount(1).WithAction("Sales.getVendors"),
+			want: "[p=Reporter.properties, a=1, Sales.getVendors]",
+		},
+		{
+			name: "full report query",
+			q: NewQueryInput().
+				WithAccount(1).
+				WithAction("Sales.getReport").
+				WithVendor(2).
+				WithArgs("Summary", "Daily", "20200101"),
+			want: "[p=Reporter.properties, a=1, Sales.getReport, 2,Summary,Daily,20200101]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.q.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryInputEncode(t *testing.T) {
+	q := NewQueryInput().WithAccount(1).WithAction("Sales.getReport").WithVendor(2).
+		WithArgs("Summary", "Daily", "20200101")
+	want := "%5Bp%3DReporter.properties%2C+a%3D1%2C+Sales.getReport%2C+2%2CSummary%2CDaily%2C20200101%5D"
+	if got := q.Encode(); got != want {
+		t.Errorf("Encode() = %q, want %q", got, want)
+	}
+}
+
+func TestRequestFluentChainMatchesQueryInput(t *testing.T) {
+	req := NewRequest(Finance).
+		WithAccount(1).
+		WithAction("Finance.getReport").
+		WithVendor(2).
+		WithArgs("WW", "Financial", "2020", "01")
+
+	if req.Target != Finance {
+		t.Errorf("Target = %v, want Finance", req.Target)
+	}
+
+	want := NewQueryInput().
+		WithAccount(1).
+		WithAction("Finance.getReport").
+		WithVendor(2).
+		WithArgs("WW", "Financial", "2020", "01").
+		String()
+	if got := req.Query.String(); got != want {
+		t.Errorf("Query.String() = %q, want %q", got, want)
+	}
+	if got := req.Encode(); got != req.Query.Encode() {
+		t.Errorf("Encode() = %q, want %q", got, req.Query.Encode())
+	}
+}
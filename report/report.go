@@ -0,0 +1,321 @@
+// Package report decodes the report payloads returned by the iTunes
+// Connect/App Store Connect Reporter service: gzip-compressed TSV files for
+// successful requests, or an XML error blob when Apple rejects the query.
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the MM/DD/YYYY format used throughout sales and finance
+// report columns.
+const dateLayout = "01/02/2006"
+
+// gzipMagic is the two-byte gzip member header, used to tell a compressed
+// report apart from a raw XML error body.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReporterError is the typed form of the XML error payload Apple returns in
+// place of a report, e.g. <Error><Code>213</Code><Message>No report
+// available</Message></Error>.
+type ReporterError struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    int      `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func (e *ReporterError) Error() string {
+	return fmt.Sprintf("reporter: error %d: %s", e.Code, e.Message)
+}
+
+// ParseError reports whether body is an Apple XML <Error> payload, as
+// opposed to a gzip-compressed report, and decodes it if so. Callers that
+// already have the full response body in memory (rather than a stream to
+// ungzip) should use this instead of DecodeSalesReport/DecodeFinanceReport.
+func ParseError(body []byte) (*ReporterError, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, gzipMagic) {
+		return nil, false
+	}
+	var rerr ReporterError
+	if err := xml.Unmarshal(trimmed, &rerr); err != nil {
+		return nil, false
+	}
+	return &rerr, true
+}
+
+// SalesRow is one row of a Sales.getReport TSV payload.
+type SalesRow struct {
+	Provider              string
+	ProviderCountry       string
+	SKU                   string
+	Developer             string
+	Title                 string
+	Version               string
+	ProductTypeIdentifier string
+	Units                 int
+	DeveloperProceeds     float64
+	BeginDate             time.Time
+	EndDate               time.Time
+	CustomerCurrency      string
+	CountryCode           string
+	CurrencyOfProceeds    string
+	AppleIdentifier       string
+	CustomerPrice         float64
+	PromoCode             string
+	ParentIdentifier      string
+	Subscription          string
+	Period                string
+	Category              string
+	CMB                   string
+	Device                string
+	SupportedPlatforms    string
+	ProceedsReason        string
+	PreservedPricing      string
+	Client                string
+}
+
+// FinanceRow is one row of a Finance.getReport TSV payload.
+type FinanceRow struct {
+	StartDate             time.Time
+	EndDate               time.Time
+	UPC                   string
+	ISRC                  string
+	VendorIdentifier      string
+	Quantity              int
+	PartnerShare          float64
+	ExtendedPartnerShare  float64
+	PartnerShareCurrency  string
+	SalesOrReturn         string
+	AppleIdentifier       string
+	Title                 string
+	ArtistShowDeveloper   string
+	LabelStudioNetworkDev string
+	CountryOfSale         string
+	PreOrderFlag          string
+	PromoCode             string
+	CustomerPrice         float64
+	CustomerCurrency      string
+}
+
+// DecodeSalesReport decodes a Sales.getReport response body. It transparently
+// ungzips the payload and parses the TSV into typed rows. If the payload is
+// instead an Apple XML error body, DecodeSalesReport returns a *ReporterError.
+func DecodeSalesReport(r io.Reader) ([]SalesRow, error) {
+	tsv, err := ungzipOrError(r)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := readTSV(tsv)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	rows := make([]SalesRow, 0, len(lines)-1)
+	for _, cols := range lines[1:] {
+		row, err := parseSalesRow(cols)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// DecodeFinanceReport decodes a Finance.getReport response body. It
+// transparently ungzips the payload and parses the TSV into typed rows. If
+// the payload is instead an Apple XML error body, DecodeFinanceReport returns
+// a *ReporterError.
+func DecodeFinanceReport(r io.Reader) ([]FinanceRow, error) {
+	tsv, err := ungzipOrError(r)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := readTSV(tsv)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	rows := make([]FinanceRow, 0, len(lines)-1)
+	for _, cols := range lines[1:] {
+		row, err := parseFinanceRow(cols)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ungzipOrError peeks at r to tell a gzip-compressed report apart from a raw
+// XML error body, returning the decompressed TSV bytes or a *ReporterError.
+func ungzipOrError(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	peek, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(peek, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("report: open gzip reader: %w", err)
+		}
+		defer gz.Close()
+		return ioutil.ReadAll(gz)
+	}
+
+	body, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	var reporterErr ReporterError
+	if err := xml.Unmarshal(body, &reporterErr); err != nil {
+		return nil, fmt.Errorf("report: payload is neither gzip nor a recognizable error: %w", err)
+	}
+	return nil, &reporterErr
+}
+
+// readTSV splits raw tab-separated bytes into rows of columns, skipping a
+// trailing blank line.
+func readTSV(data []byte) ([][]string, error) {
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	rawLines := strings.Split(text, "\n")
+	lines := make([][]string, 0, len(rawLines))
+	for _, line := range rawLines {
+		line = strings.TrimRight(line, "\r")
+		lines = append(lines, strings.Split(line, "\t"))
+	}
+	return lines, nil
+}
+
+func parseSalesRow(cols []string) (SalesRow, error) {
+	const wantCols = 27
+	if len(cols) < wantCols {
+		return SalesRow{}, fmt.Errorf("report: sales row has %d columns, want at least %d", len(cols), wantCols)
+	}
+
+	units, err := strconv.Atoi(cols[7])
+	if err != nil {
+		return SalesRow{}, fmt.Errorf("report: parse Units: %w", err)
+	}
+	proceeds, err := strconv.ParseFloat(cols[8], 64)
+	if err != nil {
+		return SalesRow{}, fmt.Errorf("report: parse Developer Proceeds: %w", err)
+	}
+	begin, err := time.Parse(dateLayout, cols[9])
+	if err != nil {
+		return SalesRow{}, fmt.Errorf("report: parse Begin Date: %w", err)
+	}
+	end, err := time.Parse(dateLayout, cols[10])
+	if err != nil {
+		return SalesRow{}, fmt.Errorf("report: parse End Date: %w", err)
+	}
+	price, err := strconv.ParseFloat(cols[15], 64)
+	if err != nil {
+		return SalesRow{}, fmt.Errorf("report: parse Customer Price: %w", err)
+	}
+
+	return SalesRow{
+		Provider:              cols[0],
+		ProviderCountry:       cols[1],
+		SKU:                   cols[2],
+		Developer:             cols[3],
+		Title:                 cols[4],
+		Version:               cols[5],
+		ProductTypeIdentifier: cols[6],
+		Units:                 units,
+		DeveloperProceeds:     proceeds,
+		BeginDate:             begin,
+		EndDate:               end,
+		CustomerCurrency:      cols[11],
+		CountryCode:           cols[12],
+		CurrencyOfProceeds:    cols[13],
+		AppleIdentifier:       cols[14],
+		CustomerPrice:         price,
+		PromoCode:             cols[16],
+		ParentIdentifier:      cols[17],
+		Subscription:          cols[18],
+		Period:                cols[19],
+		Category:              cols[20],
+		CMB:                   cols[21],
+		Device:                cols[22],
+		SupportedPlatforms:    cols[23],
+		ProceedsReason:        cols[24],
+		PreservedPricing:      cols[25],
+		Client:                cols[26],
+	}, nil
+}
+
+func parseFinanceRow(cols []string) (FinanceRow, error) {
+	const wantCols = 19
+	if len(cols) < wantCols {
+		return FinanceRow{}, fmt.Errorf("report: finance row has %d columns, want at least %d", len(cols), wantCols)
+	}
+
+	start, err := time.Parse(dateLayout, cols[0])
+	if err != nil {
+		return FinanceRow{}, fmt.Errorf("report: parse Start Date: %w", err)
+	}
+	end, err := time.Parse(dateLayout, cols[1])
+	if err != nil {
+		return FinanceRow{}, fmt.Errorf("report: parse End Date: %w", err)
+	}
+	quantity, err := strconv.Atoi(cols[5])
+	if err != nil {
+		return FinanceRow{}, fmt.Errorf("report: parse Quantity: %w", err)
+	}
+	partnerShare, err := strconv.ParseFloat(cols[6], 64)
+	if err != nil {
+		return FinanceRow{}, fmt.Errorf("report: parse Partner Share: %w", err)
+	}
+	extendedPartnerShare, err := strconv.ParseFloat(cols[7], 64)
+	if err != nil {
+		return FinanceRow{}, fmt.Errorf("report: parse Extended Partner Share: %w", err)
+	}
+	customerPrice, err := strconv.ParseFloat(cols[17], 64)
+	if err != nil {
+		return FinanceRow{}, fmt.Errorf("report: parse Customer Price: %w", err)
+	}
+
+	return FinanceRow{
+		StartDate:             start,
+		EndDate:               end,
+		UPC:                   cols[2],
+		ISRC:                  cols[3],
+		VendorIdentifier:      cols[4],
+		Quantity:              quantity,
+		PartnerShare:          partnerShare,
+		ExtendedPartnerShare:  extendedPartnerShare,
+		PartnerShareCurrency:  cols[8],
+		SalesOrReturn:         cols[9],
+		AppleIdentifier:       cols[10],
+		Title:                 cols[11],
+		ArtistShowDeveloper:   cols[12],
+		LabelStudioNetworkDev: cols[13],
+		CountryOfSale:         cols[14],
+		PreOrderFlag:          cols[15],
+		PromoCode:             cols[16],
+		CustomerPrice:         customerPrice,
+		CustomerCurrency:      cols[18],
+	}, nil
+}
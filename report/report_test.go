@@ -0,0 +1,180 @@
+package report
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gzipString(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeSalesReport(t *testing.T) {
+	header := "Provider\tProvider Country\tSKU\tDeveloper\tTitle\tVersion\tProduct Type Identifier\t" +
+		"Units\tDeveloper Proceeds\tBegin Date\tEnd Date\tCustomer Currency\tCountry Code\t" +
+		"Currency of Proceeds\tApple Identifier\tCustomer Price\tPromo Code\tParent Identifier\t" +
+		"Subscription\tPeriod\tCategory\tCMB\tDevice\tSupported Platforms\tProceeds Reason\t" +
+		"Preserved Pricing\tClient"
+	row := "Apple\tUS\tcom.example.app\tExample Inc\tExample App\t1.2.3\t1F\t" +
+		"3\t2.10\t01/15/2020\t01/15/2020\tUSD\tUS\t" +
+		"USD\t123456789\t0.99\t\t\t" +
+		"\t\t6014\t\tiPhone\tiOS\t\t" +
+		"1\tApple"
+	tsv := header + "\n" + row + "\n"
+
+	rows, err := DecodeSalesReport(bytes.NewReader(gzipString(t, tsv)))
+	if err != nil {
+		t.Fatalf("DecodeSalesReport: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	want := SalesRow{
+		Provider:              "Apple",
+		ProviderCountry:       "US",
+		SKU:                   "com.example.app",
+		Developer:             "Example Inc",
+		Title:                 "Example App",
+		Version:               "1.2.3",
+		ProductTypeIdentifier: "1F",
+		Units:                 3,
+		DeveloperProceeds:     2.10,
+		BeginDate:             mustParseDate(t, "01/15/2020"),
+		EndDate:               mustParseDate(t, "01/15/2020"),
+		CustomerCurrency:      "USD",
+		CountryCode:           "US",
+		CurrencyOfProceeds:    "USD",
+		AppleIdentifier:       "123456789",
+		CustomerPrice:         0.99,
+		PromoCode:             "",
+		ParentIdentifier:      "",
+		Subscription:          "",
+		Period:                "",
+		Category:              "6014",
+		CMB:                   "",
+		Device:                "iPhone",
+		SupportedPlatforms:    "iOS",
+		ProceedsReason:        "",
+		PreservedPricing:      "1",
+		Client:                "Apple",
+	}
+	if got := rows[0]; got != want {
+		t.Fatalf("row mismatch:\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestDecodeFinanceReport(t *testing.T) {
+	header := "Start Date\tEnd Date\tUPC\tISRC\tVendor Identifier\tQuantity\tPartner Share\t" +
+		"Extended Partner Share\tPartner Share Currency\tSales or Return\tApple Identifier\tTitle\t" +
+		"Artist/Show/Developer\tLabel/Studio/Network/Developer\tCountry Of Sale\tPre-order Flag\t" +
+		"Promo Code\tCustomer Price\tCustomer Currency"
+	row := "01/15/2020\t02/15/2020\t\t\tV123\t2\t1.40\t" +
+		"1.40\tUSD\tS\t123456789\tExample App\t" +
+		"Example Inc\tExample Inc\tUS\t\t" +
+		"\t0.99\tUSD"
+	tsv := header + "\n" + row + "\n"
+
+	rows, err := DecodeFinanceReport(bytes.NewReader(gzipString(t, tsv)))
+	if err != nil {
+		t.Fatalf("DecodeFinanceReport: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	want := FinanceRow{
+		StartDate:             mustParseDate(t, "01/15/2020"),
+		EndDate:               mustParseDate(t, "02/15/2020"),
+		UPC:                   "",
+		ISRC:                  "",
+		VendorIdentifier:      "V123",
+		Quantity:              2,
+		PartnerShare:          1.40,
+		ExtendedPartnerShare:  1.40,
+		PartnerShareCurrency:  "USD",
+		SalesOrReturn:         "S",
+		AppleIdentifier:       "123456789",
+		Title:                 "Example App",
+		ArtistShowDeveloper:   "Example Inc",
+		LabelStudioNetworkDev: "Example Inc",
+		CountryOfSale:         "US",
+		PreOrderFlag:          "",
+		PromoCode:             "",
+		CustomerPrice:         0.99,
+		CustomerCurrency:      "USD",
+	}
+	if got := rows[0]; got != want {
+		t.Fatalf("row mismatch:\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestDecodeSalesReportXMLError(t *testing.T) {
+	body := `<Error><Code>213</Code><Message>No report available</Message></Error>`
+	_, err := DecodeSalesReport(strings.NewReader(body))
+	rerr, ok := err.(*ReporterError)
+	if !ok {
+		t.Fatalf("got error %v (%T), want *ReporterError", err, err)
+	}
+	if rerr.Code != 213 || rerr.Message != "No report available" {
+		t.Fatalf("got %+v, want Code=213 Message=%q", rerr, "No report available")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		wantOK   bool
+		wantCode int
+	}{
+		{
+			name:     "xml error",
+			body:     []byte(`<Error><Code>601</Code><Message>exceeded the number of concurrent requests</Message></Error>`),
+			wantOK:   true,
+			wantCode: 601,
+		},
+		{
+			name:   "gzip body",
+			body:   gzipString(t, "Provider\n"),
+			wantOK: false,
+		},
+		{
+			name:   "plain text",
+			body:   []byte("1"),
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rerr, ok := ParseError(tt.body)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && rerr.Code != tt.wantCode {
+				t.Fatalf("Code = %d, want %d", rerr.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func mustParseDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	tm, err := time.Parse(dateLayout, s)
+	if err != nil {
+		t.Fatalf("parse date %q: %v", s, err)
+	}
+	return tm
+}
@@ -1,23 +1,78 @@
 package ireporter
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
+
+	"github.com/chapsuk/ireporter/internal/query"
+	"github.com/chapsuk/ireporter/report"
 )
 
 var version = 1.0
 var salesEndpoint = "https://reportingitc-reporter.apple.com/reportservice/sales/v1"
 var financeEndpoint = "https://reportingitc-reporter.apple.com/reportservice/finance/v1"
 
+// defaultHTTPTimeout is used when Config.HTTPTimeout is not set.
+const defaultHTTPTimeout = 30 * time.Second
+
+// defaultRetryBaseDelay and defaultRetryMaxDelay are used when
+// Config.RetryBaseDelay/Config.RetryMaxDelay are not set and
+// Config.MaxRetries is greater than zero.
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// rateLimitMessage is the substring Apple's Reporter service uses in its XML
+// error body when an account has too many concurrent requests in flight.
+const rateLimitMessage = "exceeded the number of concurrent requests"
+
+// Target identifies which Reporter service endpoint a QueryRequest targets.
+// It is exposed so callers can build queries for endpoints this package
+// doesn't wrap yet, e.g. Sales.getReportVersion.
+type Target = query.Target
+
+// SalesTarget and FinanceTarget select the sales and finance endpoints,
+// respectively, when building a custom QueryRequest for use with Client.Do.
+const (
+	SalesTarget   = query.Sales
+	FinanceTarget = query.Finance
+)
+
+// QueryInput fluently builds the queryInput string accepted by Sales.* and
+// Finance.* actions.
+type QueryInput = query.QueryInput
+
+// NewQueryInput returns an empty, ready-to-configure QueryInput.
+func NewQueryInput() *QueryInput {
+	return query.NewQueryInput()
+}
+
+// QueryRequest pairs a QueryInput with the Target it should be sent to. Use
+// it together with Client.Do to call endpoints this package doesn't provide
+// a typed method for yet.
+type QueryRequest = query.Request
+
+// NewQueryRequest returns a QueryRequest targeting the given service, with
+// an empty QueryInput ready for fluent configuration.
+func NewQueryRequest(target Target) *QueryRequest {
+	return query.NewRequest(target)
+}
+
 // Client is reporter client
 type Client struct {
-	cfg Config
+	cfg        Config
+	httpClient *http.Client
 }
 
 // Config base properties
@@ -25,6 +80,34 @@ type Config struct {
 	UserID   string
 	Password string
 	Mode     string
+
+	// HTTPTimeout bounds the duration of a single request/response round
+	// trip. Defaults to defaultHTTPTimeout when zero.
+	HTTPTimeout time.Duration
+
+	// HTTPClient, when set, is used instead of a client constructed from
+	// HTTPTimeout. Useful for tests or for sharing transports/middleware.
+	HTTPClient *http.Client
+
+	// MaxRetries is the number of additional attempts send makes after a
+	// transient failure (network error or HTTP 5xx). Zero disables retry.
+	MaxRetries int
+
+	// RetryBaseDelay is the backoff before the first retry; later retries
+	// back off exponentially from it. Jitter picks each actual delay between
+	// half the backoff and the full backoff. Defaults to
+	// defaultRetryBaseDelay when zero.
+	RetryBaseDelay time.Duration
+
+	// RetryMaxDelay caps the backoff delay between retries, and is also used
+	// as the cooldown after Apple reports too many concurrent requests.
+	// Defaults to defaultRetryMaxDelay when zero.
+	RetryMaxDelay time.Duration
+
+	// RetryHook, when set, is called before each retry with the 1-based
+	// attempt number and the error that triggered it, so callers can log or
+	// emit metrics.
+	RetryHook func(attempt int, err error)
 }
 
 // Request to Reporter endpoints
@@ -44,97 +127,270 @@ func NewClient(cfg Config) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		timeout := cfg.HTTPTimeout
+		if timeout <= 0 {
+			timeout = defaultHTTPTimeout
+		}
+		httpClient = &http.Client{Timeout: timeout}
+	}
 	return &Client{
-		cfg: cfg,
+		cfg:        cfg,
+		httpClient: httpClient,
 	}, nil
 }
 
 // GetSalesStatus return Sales.getStatus response
 func (c Client) GetSalesStatus() ([]byte, error) {
-	req := c.getBaseRequest()
-	req.QueryInput = "%5Bp%3DReporter.properties%2C+Sales.getStatus%5D"
-	return c.send(salesEndpoint, req)
+	return c.GetSalesStatusContext(context.Background())
+}
+
+// GetSalesStatusContext is GetSalesStatus with a caller-supplied context for
+// cancellation and deadlines.
+func (c Client) GetSalesStatusContext(ctx context.Context) ([]byte, error) {
+	req := query.NewRequest(query.Sales).WithAction("Sales.getStatus")
+	return c.sendQuery(ctx, req)
 }
 
 // GetFinanceStatus return Finance.getStatus response
 func (c Client) GetFinanceStatus() ([]byte, error) {
-	req := c.getBaseRequest()
-	req.QueryInput = "%5Bp%3DReporter.properties%2C+Finance.getStatus%5D"
-	return c.send(financeEndpoint, req)
+	return c.GetFinanceStatusContext(context.Background())
+}
+
+// GetFinanceStatusContext is GetFinanceStatus with a caller-supplied context
+// for cancellation and deadlines.
+func (c Client) GetFinanceStatusContext(ctx context.Context) ([]byte, error) {
+	req := query.NewRequest(query.Finance).WithAction("Finance.getStatus")
+	return c.sendQuery(ctx, req)
 }
 
 // GetSalesAccounts return Sales.getAccounts response
 func (c Client) GetSalesAccounts() ([]byte, error) {
-	req := c.getBaseRequest()
-	req.QueryInput = "%5Bp%3DReporter.properties%2C+Sales.getAccounts%5D"
-	return c.send(salesEndpoint, req)
+	return c.GetSalesAccountsContext(context.Background())
+}
+
+// GetSalesAccountsContext is GetSalesAccounts with a caller-supplied context
+// for cancellation and deadlines.
+func (c Client) GetSalesAccountsContext(ctx context.Context) ([]byte, error) {
+	req := query.NewRequest(query.Sales).WithAction("Sales.getAccounts")
+	return c.sendQuery(ctx, req)
 }
 
 // GetFinanceAccounts return Finance.getAccounts response
 func (c Client) GetFinanceAccounts() ([]byte, error) {
-	req := c.getBaseRequest()
-	req.QueryInput = "%5Bp%3DReporter.properties%2C+Finance.getAccounts%5D"
-	return c.send(financeEndpoint, req)
+	return c.GetFinanceAccountsContext(context.Background())
+}
+
+// GetFinanceAccountsContext is GetFinanceAccounts with a caller-supplied
+// context for cancellation and deadlines.
+func (c Client) GetFinanceAccountsContext(ctx context.Context) ([]byte, error) {
+	req := query.NewRequest(query.Finance).WithAction("Finance.getAccounts")
+	return c.sendQuery(ctx, req)
 }
 
 // GetSalesVendors return Sales.getVendors response
 func (c Client) GetSalesVendors(account int) ([]byte, error) {
+	return c.GetSalesVendorsContext(context.Background(), account)
+}
+
+// GetSalesVendorsContext is GetSalesVendors with a caller-supplied context
+// for cancellation and deadlines.
+func (c Client) GetSalesVendorsContext(ctx context.Context, account int) ([]byte, error) {
 	if account <= 0 {
 		return nil, errors.New("Wrong vendor number")
 	}
-	req := c.getBaseRequest()
-	req.QueryInput = fmt.Sprintf("%%5Bp%%3DReporter.properties%%2C+a%%3D%d%%2C+Sales.getVendors%%5D", account)
-	return c.send(salesEndpoint, req)
+	req := query.NewRequest(query.Sales).WithAccount(account).WithAction("Sales.getVendors")
+	return c.sendQuery(ctx, req)
 }
 
 // GetFinanceVendorsAndRegions return Finance.getVendors response
 func (c Client) GetFinanceVendorsAndRegions(account int) ([]byte, error) {
+	return c.GetFinanceVendorsAndRegionsContext(context.Background(), account)
+}
+
+// GetFinanceVendorsAndRegionsContext is GetFinanceVendorsAndRegions with a
+// caller-supplied context for cancellation and deadlines.
+func (c Client) GetFinanceVendorsAndRegionsContext(ctx context.Context, account int) ([]byte, error) {
 	if account <= 0 {
 		return nil, errors.New("Wrong vendor number")
 	}
-	req := c.getBaseRequest()
-	req.QueryInput = fmt.Sprintf("%%5Bp%%3DReporter.properties%%2C+a%%3D%d%%2C+Finance.getVendorsAndRegions%%5D", account)
-	return c.send(financeEndpoint, req)
+	req := query.NewRequest(query.Finance).WithAccount(account).WithAction("Finance.getVendorsAndRegions")
+	return c.sendQuery(ctx, req)
 }
 
 // GetSalesReport return Sales.getReport response (is report file or error)
 func (c Client) GetSalesReport(account, vendor int, reportType, reportSubType, dateType, date string) ([]byte, error) {
+	return c.GetSalesReportContext(context.Background(), account, vendor, reportType, reportSubType, dateType, date)
+}
+
+// GetSalesReportContext is GetSalesReport with a caller-supplied context for
+// cancellation and deadlines.
+func (c Client) GetSalesReportContext(ctx context.Context, account, vendor int, reportType, reportSubType, dateType, date string) ([]byte, error) {
 	err := validateSalesReportArgs(account, vendor, reportType, reportSubType, dateType, date)
 	if err != nil {
 		return nil, err
 	}
-	req := c.getBaseRequest()
-	qI := "%%5Bp%%3DReporter.properties%%2C+a%%3D%d%%2C+Sales.getReport%%2C+%d%%2C%s%%2C%s%%2C%s%%2C%s%%5D"
-	req.QueryInput = fmt.Sprintf(qI, account, vendor, reportType, reportSubType, dateType, date)
-	return c.send(salesEndpoint, req)
+	req := query.NewRequest(query.Sales).
+		WithAccount(account).
+		WithAction("Sales.getReport").
+		WithVendor(vendor).
+		WithArgs(reportType, reportSubType, dateType, date)
+	return c.sendQuery(ctx, req)
 }
 
 // GetFinanceReport return Finance.getReport response (is report file or error)
-// func (c Client) GetFinanceReport() ([]byte, error) {
-// TODO implement me
-// }
+func (c Client) GetFinanceReport(account, vendor int, regionCode, reportType, fiscalYear, fiscalPeriod string) ([]byte, error) {
+	return c.GetFinanceReportContext(context.Background(), account, vendor, regionCode, reportType, fiscalYear, fiscalPeriod)
+}
+
+// GetFinanceReportContext is GetFinanceReport with a caller-supplied context
+// for cancellation and deadlines.
+func (c Client) GetFinanceReportContext(ctx context.Context, account, vendor int, regionCode, reportType, fiscalYear, fiscalPeriod string) ([]byte, error) {
+	err := validateFinanceReportArgs(account, vendor, regionCode, reportType, fiscalYear, fiscalPeriod)
+	if err != nil {
+		return nil, err
+	}
+	req := query.NewRequest(query.Finance).
+		WithAccount(account).
+		WithAction("Finance.getReport").
+		WithVendor(vendor).
+		WithArgs(regionCode, reportType, fiscalYear, fiscalPeriod)
+	return c.sendQuery(ctx, req)
+}
+
+// Do sends a custom QueryRequest, for Sales.* and Finance.* actions this
+// package does not provide a typed method for yet (e.g.
+// Sales.getReportVersion, Finance.getReportVersion). Build req with
+// NewQueryRequest.
+func (c Client) Do(ctx context.Context, req *QueryRequest) ([]byte, error) {
+	return c.sendQuery(ctx, req)
+}
+
+// sendQuery encodes req's QueryInput into a base Request and posts it to the
+// endpoint for req's Target.
+func (c Client) sendQuery(ctx context.Context, req *query.Request) ([]byte, error) {
+	base := c.getBaseRequest()
+	base.QueryInput = req.Encode()
+	return c.send(ctx, c.endpointURL(req.Target), base)
+}
+
+// endpointURL returns the Reporter service URL for target.
+func (c Client) endpointURL(target query.Target) string {
+	if target == query.Finance {
+		return financeEndpoint
+	}
+	return salesEndpoint
+}
 
-func (c Client) send(endpoint string, r Request) ([]byte, error) {
+func (c Client) send(ctx context.Context, endpoint string, r Request) ([]byte, error) {
 	q, err := json.Marshal(r)
 	if err != nil {
 		return nil, err
 	}
 	log.Print(string(q))
-	query := fmt.Sprintf("jsonRequest=%s", string(q))
-	resp, err := http.Post(endpoint, "application/x-www-form-urlencoded", strings.NewReader(query))
+	payload := fmt.Sprintf("jsonRequest=%s", string(q))
+
+	baseDelay := c.cfg.RetryBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	maxDelay := c.cfg.RetryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	maxRetries := c.cfg.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := backoffDelay(attempt, baseDelay, maxDelay)
+			if isRateLimitErr(lastErr) {
+				delay = maxDelay
+			}
+			if c.cfg.RetryHook != nil {
+				c.cfg.RetryHook(attempt, lastErr)
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		body, retryable, err := c.attempt(ctx, endpoint, payload)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+		if !retryable {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// attempt performs a single HTTP round trip and classifies the outcome:
+// retryable reports whether send should back off and try again.
+func (c Client) attempt(ctx context.Context, endpoint, payload string) (body []byte, retryable bool, err error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(payload))
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	defer resp.Body.Close()
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	body, err := ioutil.ReadAll(resp.Body)
+	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, err
+		return nil, true, err
+	}
+	defer func() {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	// Apple sometimes signals its concurrent-request rate limit via an XML
+	// <Error> body on a non-200 status, so check for it before the status
+	// code switch decides whether to fail fast. Other XML errors are returned
+	// as-is and retried only on a 5xx status.
+	if rerr, ok := report.ParseError(body); ok {
+		return nil, isRateLimitErr(rerr) || resp.StatusCode >= http.StatusInternalServerError, rerr
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(string(body))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, false, errors.New(string(body))
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, true, fmt.Errorf("reporter: server error %d: %s", resp.StatusCode, body)
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, errors.New(string(body))
 	}
-	return body, nil
+
+	return body, false, nil
+}
+
+// isRateLimitErr reports whether err is Apple's "too many concurrent
+// requests" error, which warrants a longer cooldown before retrying.
+func isRateLimitErr(err error) bool {
+	rerr, ok := err.(*report.ReporterError)
+	return ok && strings.Contains(strings.ToLower(rerr.Message), rateLimitMessage)
+}
+
+// backoffDelay computes the exponential backoff delay for the given 1-based
+// attempt, with jitter, capped at max.
+func backoffDelay(attempt int, base, max time.Duration) time.Duration {
+	delay := base << uint(attempt-1)
+	if delay <= 0 || delay > max {
+		delay = max
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)/2+1))
 }
 
 func (c Client) getBaseRequest() Request {
@@ -203,4 +459,33 @@ func validateSalesReportArgs(account, vendor int, reportType, reportSubType, dat
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func validateFinanceReportArgs(account, vendor int, regionCode, reportType, fiscalYear, fiscalPeriod string) error {
+	if account <= 0 {
+		return errors.New("Wrong account value")
+	}
+	if vendor <= 0 {
+		return errors.New("Wrong vendor value")
+	}
+	if regionCode == "" {
+		return errors.New("Wrong RegionCode, must not be empty")
+	}
+
+	switch reportType {
+	case "Financial",
+		"FinancialDetail":
+		break
+	default:
+		return errors.New("Wrong ReportType, use: Financial or FinancialDetail")
+	}
+
+	if len(fiscalYear) != 4 {
+		return errors.New("Wrong FiscalYear format, use: YYYY")
+	}
+	if len(fiscalPeriod) != 2 {
+		return errors.New("Wrong FiscalPeriod format, use: two-digit period, e.g. 01")
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package ireporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chapsuk/ireporter/report"
+)
+
+// testConfig returns a Config that passes checkConfig. The retry knobs are
+// left at the caller's zero values so each test can set
+// MaxRetries/RetryBaseDelay/RetryMaxDelay as needed.
+func testConfig() Config {
+	return Config{
+		UserID:   "user",
+		Password: "pass",
+		Mode:     "Normal",
+	}
+}
+
+// withTestEndpoints points salesEndpoint and financeEndpoint at ts for the
+// duration of a test, restoring the originals on cleanup.
+func withTestEndpoints(t *testing.T, ts *httptest.Server) {
+	t.Helper()
+	origSales, origFinance := salesEndpoint, financeEndpoint
+	salesEndpoint, financeEndpoint = ts.URL, ts.URL
+	t.Cleanup(func() {
+		salesEndpoint, financeEndpoint = origSales, origFinance
+	})
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := time.Second
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		for i := 0; i < 20; i++ {
+			delay := backoffDelay(attempt, base, max)
+			if delay < 0 {
+				t.Fatalf("attempt %d: delay = %v, want >= 0", attempt, delay)
+			}
+			if delay > max {
+				t.Fatalf("attempt %d: delay = %v, want <= max %v", attempt, delay, max)
+			}
+		}
+	}
+}
+
+func TestIsRateLimitErr(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: false,
+		},
+		{
+			name: "generic error",
+			err:  errors.New("boom"),
+			want: false,
+		},
+		{
+			name: "unrelated reporter error",
+			err:  &report.ReporterError{Code: 213, Message: "No report available"},
+			want: false,
+		},
+		{
+			name: "rate limit reporter error",
+			err:  &report.ReporterError{Code: 601, Message: "You have exceeded the number of concurrent requests permitted for your account."},
+			want: true,
+		},
+		{
+			name: "rate limit is case-insensitive",
+			err:  &report.ReporterError{Code: 601, Message: "EXCEEDED THE NUMBER OF CONCURRENT REQUESTS"},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRateLimitErr(tt.err); got != tt.want {
+				t.Errorf("isRateLimitErr(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSendRetriesOnServerErrorThenSucceeds exercises send/attempt against a
+// real httptest.Server that fails once with a 5xx before succeeding, proving
+// the retry loop actually retries and returns the eventual success.
+func TestSendRetriesOnServerErrorThenSucceeds(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("temporarily unavailable"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("status-ok"))
+	}))
+	defer ts.Close()
+	withTestEndpoints(t, ts)
+
+	cfg := testConfig()
+	cfg.MaxRetries = 1
+	cfg.RetryBaseDelay = time.Millisecond
+	cfg.RetryMaxDelay = 10 * time.Millisecond
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	body, err := client.GetSalesStatusContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetSalesStatusContext() error = %v", err)
+	}
+	if string(body) != "status-ok" {
+		t.Errorf("body = %q, want %q", body, "status-ok")
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("requests = %d, want 2 (one failure, one retry)", got)
+	}
+}
+
+// TestSendFailsFastOnAuthError proves 401/403 responses are not retried:
+// they are Apple rejecting the credentials outright, so retrying wastes the
+// configured attempts on a request that can never succeed.
+func TestSendFailsFastOnAuthError(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("bad credentials"))
+	}))
+	defer ts.Close()
+	withTestEndpoints(t, ts)
+
+	cfg := testConfig()
+	cfg.MaxRetries = 3
+	cfg.RetryBaseDelay = time.Millisecond
+	cfg.RetryMaxDelay = 10 * time.Millisecond
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	_, err = client.GetSalesStatusContext(context.Background())
+	if err == nil {
+		t.Fatal("GetSalesStatusContext() error = nil, want non-nil")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("requests = %d, want 1 (no retry after 401)", got)
+	}
+}
+
+// TestSendUsesRetryMaxDelayOnRateLimit proves a rate-limit XML error (even on
+// a non-200 status) triggers the longer RetryMaxDelay cooldown rather than
+// the much shorter exponential-backoff delay the same RetryBaseDelay would
+// otherwise produce on attempt 1.
+func TestSendUsesRetryMaxDelayOnRateLimit(t *testing.T) {
+	const retryMaxDelay = 150 * time.Millisecond
+	var requests int32
+	var firstRequestAt, secondRequestAt time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			firstRequestAt = time.Now()
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`<Error><Code>601</Code><Message>You have exceeded the number of concurrent requests permitted for your account.</Message></Error>`))
+			return
+		}
+		secondRequestAt = time.Now()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("status-ok"))
+	}))
+	defer ts.Close()
+	withTestEndpoints(t, ts)
+
+	cfg := testConfig()
+	cfg.MaxRetries = 1
+	cfg.RetryBaseDelay = time.Millisecond
+	cfg.RetryMaxDelay = retryMaxDelay
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	body, err := client.GetSalesStatusContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetSalesStatusContext() error = %v", err)
+	}
+	if string(body) != "status-ok" {
+		t.Errorf("body = %q, want %q", body, "status-ok")
+	}
+
+	gap := secondRequestAt.Sub(firstRequestAt)
+	if gap < retryMaxDelay {
+		t.Errorf("retry gap = %v, want >= RetryMaxDelay %v", gap, retryMaxDelay)
+	}
+}
+
+// TestSendContextCancellationReturnsPromptly proves an in-flight request is
+// aborted as soon as its context is canceled, instead of blocking until the
+// (slow or hanging) server responds.
+func TestSendContextCancellationReturnsPromptly(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("status-ok"))
+	}))
+	defer ts.Close()
+	withTestEndpoints(t, ts)
+
+	cfg := testConfig()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.GetSalesReportContext(ctx, 1, 2, "Sales", "Summary", "Daily", "20200101")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetSalesReportContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("GetSalesReportContext() took %v, want well under the server's hang", elapsed)
+	}
+}